@@ -0,0 +1,80 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// adminBackendRequest is the JSON body for POST /admin/backends.
+type adminBackendRequest struct {
+	ID        string `json:"id"`
+	BaseURL   string `json:"baseUrl"`
+	GRPCAddr  string `json:"grpcAddr"`
+	PublicKey string `json:"publicKey"` // base64-encoded NaCl public key
+}
+
+// AdminBackendsHandler serves POST /admin/backends and DELETE /admin/backends/{id},
+// guarded by the shared secret in config.BackendAdminSecret (sent as the
+// X-FFZ-Admin-Secret header), so operators can add or drain a backend
+// endpoint from the RPC pool without restarting the socketserver.
+//
+// This is the HTTP analogue of the admin_addTrustedPeer / admin_removeTrustedPeer
+// remote commands: an unscheduled, operator-triggered change to live state.
+func (backend *backendInfo) AdminBackendsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := backend.config.BackendAdminSecret
+		if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-FFZ-Admin-Secret")), []byte(secret)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			backend.handleAddBackend(w, r)
+		case http.MethodDelete:
+			backend.handleRemoveBackend(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (backend *backendInfo) handleAddBackend(w http.ResponseWriter, r *http.Request) {
+	var req adminBackendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("bad request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(req.PublicKey)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad publicKey: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := backend.AddBackendEndpoint(req.ID, req.BaseURL, req.GRPCAddr, publicKey); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (backend *backendInfo) handleRemoveBackend(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/admin/backends/")
+	if id == "" || id == r.URL.Path {
+		http.Error(w, "missing backend id", http.StatusBadRequest)
+		return
+	}
+
+	if err := backend.RemoveBackendEndpoint(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
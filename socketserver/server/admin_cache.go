@@ -0,0 +1,45 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AdminCacheHandler serves DELETE /admin/cache/{key}, guarded by the shared
+// secret in config.BackendAdminSecret (sent as the X-FFZ-Admin-Secret
+// header). This is how the backend invalidates a cached RPC response across
+// the whole fleet: it calls this on any one node, which deletes the key from
+// the shared ResponseCache and (for the Redis backend) publishes the
+// invalidation so every other node's cache agrees immediately instead of
+// waiting out the entry's TTL.
+func (backend *backendInfo) AdminCacheHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := backend.config.BackendAdminSecret
+		if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-FFZ-Admin-Secret")), []byte(secret)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		encodedKey := strings.TrimPrefix(r.URL.Path, "/admin/cache/")
+		if encodedKey == "" || encodedKey == r.URL.Path {
+			http.Error(w, "missing cache key", http.StatusBadRequest)
+			return
+		}
+
+		cacheKey, err := url.PathUnescape(encodedKey)
+		if err != nil {
+			http.Error(w, "bad cache key", http.StatusBadRequest)
+			return
+		}
+
+		backend.responseCache.Delete(cacheKey)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestAdminBackend() *backendInfo {
+	return &backendInfo{
+		config:        &ConfigFile{BackendAdminSecret: "s3cret"},
+		responseCache: newMemoryResponseCache(),
+	}
+}
+
+func TestAdminBackendsHandlerRequiresSecret(t *testing.T) {
+	backend := newTestAdminBackend()
+	handler := backend.AdminBackendsHandler()
+
+	for _, secret := range []string{"", "wrong secret"} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/backends", strings.NewReader("{}"))
+		if secret != "" {
+			req.Header.Set("X-FFZ-Admin-Secret", secret)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("secret %q: got status %d, want %d", secret, rec.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestAdminBackendsHandlerAddAndRemove(t *testing.T) {
+	backend := newTestAdminBackend()
+	handler := backend.AdminBackendsHandler()
+
+	publicKey := base64.StdEncoding.EncodeToString([]byte("0123456789012345678901234567890"))
+	body := `{"id":"extra","baseUrl":"http://extra.example/ffz","publicKey":"` + publicKey + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/admin/backends", strings.NewReader(body))
+	req.Header.Set("X-FFZ-Admin-Secret", "s3cret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add: got status %d, want %d, body %q", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	found := false
+	for _, ep := range backend.endpoints {
+		if ep.ID == "extra" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("added backend endpoint not found in pool")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/admin/backends/extra", nil)
+	req.Header.Set("X-FFZ-Admin-Secret", "s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("remove: got status %d, want %d, body %q", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+
+	for _, ep := range backend.endpoints {
+		if ep.ID == "extra" {
+			t.Fatal("removed backend endpoint still present in pool")
+		}
+	}
+}
+
+func TestAdminBackendsHandlerRemoveUnknownReturnsNotFound(t *testing.T) {
+	backend := newTestAdminBackend()
+	handler := backend.AdminBackendsHandler()
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/backends/nonexistent", nil)
+	req.Header.Set("X-FFZ-Admin-Secret", "s3cret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminCacheHandlerRequiresSecret(t *testing.T) {
+	backend := newTestAdminBackend()
+	handler := backend.AdminCacheHandler()
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/cache/some_command%2Fdata", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestAdminCacheHandlerDeletesKey(t *testing.T) {
+	backend := newTestAdminBackend()
+	handler := backend.AdminCacheHandler()
+
+	cacheKey := getCacheKey("some_command", "data")
+	backend.responseCache.SetWithTTL(cacheKey, cacheEntry{Value: "cached"}, staleCacheTTL)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/cache/"+url.PathEscape(cacheKey), nil)
+	req.Header.Set("X-FFZ-Admin-Secret", "s3cret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	if _, ok := backend.responseCache.Get(cacheKey); ok {
+		t.Fatal("cache key should have been deleted")
+	}
+}
+
+func TestAdminCacheHandlerMissingKeyReturnsBadRequest(t *testing.T) {
+	backend := newTestAdminBackend()
+	handler := backend.AdminCacheHandler()
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/cache/", nil)
+	req.Header.Set("X-FFZ-Admin-Secret", "s3cret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
@@ -8,15 +9,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/FrankerFaceZ/FrankerFaceZ/socketserver/server/naclform"
-	cache "github.com/patrickmn/go-cache"
 	"golang.org/x/crypto/nacl/box"
 	"golang.org/x/sync/singleflight"
 )
@@ -24,22 +22,16 @@ import (
 const bPathAnnounceStartup = "/startup"
 const bPathAddTopic = "/topics"
 const bPathAggStats = "/stats"
-const bPathOtherCommand = "/cmd/"
 
 type backendInfo struct {
-	HTTPClient    http.Client
-	baseURL       string
-	responseCache *cache.Cache
+	config        *ConfigFile
+	responseCache ResponseCache
 	reloadGroup   singleflight.Group
+	clusterBus    ClusterBus
 
-	postStatisticsURL  string
-	addTopicURL        string
-	announceStartupURL string
-
-	secureForm naclform.ServerInfo
-
-	lastSuccess     map[string]time.Time
-	lastSuccessLock sync.Mutex
+	endpointsMu  sync.RWMutex
+	endpoints    []*backendEndpoint
+	nextEndpoint uint64 // atomic round-robin cursor into endpoints
 }
 
 var Backend *backendInfo
@@ -47,31 +39,25 @@ var Backend *backendInfo
 func setupBackend(config *ConfigFile) *backendInfo {
 	b := new(backendInfo)
 	Backend = b
-	b.secureForm.ServerID = config.ServerID
-
-	b.HTTPClient.Timeout = 60 * time.Second
-	b.baseURL = config.BackendURL
-	// size in bytes of string payload
-	b.responseCache = cache.New(60*time.Second, 10*time.Minute)
-
-	b.announceStartupURL = fmt.Sprintf("%s%s", b.baseURL, bPathAnnounceStartup)
-	b.addTopicURL = fmt.Sprintf("%s%s", b.baseURL, bPathAddTopic)
-	b.postStatisticsURL = fmt.Sprintf("%s%s", b.baseURL, bPathAggStats)
-
-	epochTime := time.Unix(0, 0).UTC()
-	lastBackendSuccess := map[string]time.Time{
-		bPathAnnounceStartup: epochTime,
-		bPathAddTopic:        epochTime,
-		bPathAggStats:        epochTime,
-		bPathOtherCommand:    epochTime,
+	b.config = config
+
+	responseCache, err := newResponseCache(config)
+	if err != nil {
+		log.Fatalf("setting up response cache: %v", err)
 	}
-	b.lastSuccess = lastBackendSuccess
+	b.responseCache = responseCache
 
-	var theirPublic, ourPrivate [32]byte
-	copy(theirPublic[:], config.BackendPublicKey)
-	copy(ourPrivate[:], config.OurPrivateKey)
+	clusterBus, err := newClusterBus(config)
+	if err != nil {
+		log.Fatalf("setting up cluster bus: %v", err)
+	}
+	b.clusterBus = clusterBus
 
-	box.Precompute(&b.secureForm.SharedKey, &theirPublic, &ourPrivate)
+	defaultEndpoint, err := newBackendEndpoint(config, "default", config.BackendURL, config.BackendGRPCAddr, config.BackendPublicKey)
+	if err != nil {
+		log.Fatalf("setting up backend endpoint: %v", err)
+	}
+	b.endpoints = []*backendEndpoint{defaultEndpoint}
 
 	return b
 }
@@ -80,6 +66,21 @@ func getCacheKey(remoteCommand, data string) string {
 	return fmt.Sprintf("%s/%s", remoteCommand, data)
 }
 
+// staleCacheTTL is how much longer a cached response is kept around in
+// responseCache after it goes stale, purely so SendRemoteCommandCached can
+// still hand it out while a refresh is in flight.
+const staleCacheTTL = 10 * time.Minute
+
+// cacheEntry is the value type stored in backendInfo.responseCache.
+//
+// The cache's own TTL is set to the FFZ-Cache duration plus staleCacheTTL,
+// so that an entry is not evicted the instant it goes stale; freshUntil is
+// what actually decides whether a cached response is fresh or stale.
+type cacheEntry struct {
+	Value      string    `json:"value"`
+	FreshUntil time.Time `json:"freshUntil"`
+}
+
 // ErrForwardedFromBackend is an error returned by the backend server.
 type ErrForwardedFromBackend struct {
 	JSONError interface{}
@@ -98,132 +99,101 @@ var ErrAuthorizationNeeded = errors.New("Must authenticate Twitch username to us
 // SendRemoteCommandCached performs a RPC call on the backend, checking for a
 // cached response first.
 //
-// If a cached, but expired, response is found, the existing value is returned
-// and the cache is updated in the background.
-func (backend *backendInfo) SendRemoteCommandCached(remoteCommand, data string, auth AuthInfo) (string, error) {
+// Concurrent callers asking for the same remoteCommand/data are collapsed
+// into a single backend round-trip via reloadGroup, so a thundering herd of
+// clients only costs the backend one request and one cache write.
+//
+// If a cached, but stale, response is found, the stale value is returned
+// immediately and a refresh is kicked off in the background, so a caller that
+// finds a stale entry never waits on the backend. A caller that finds nothing
+// cached waits for the shared in-flight call to finish.
+func (backend *backendInfo) SendRemoteCommandCached(ctx context.Context, remoteCommand, data string, auth AuthInfo) (string, error) {
 	cacheKey := getCacheKey(remoteCommand, data)
-	cached, ok := backend.responseCache.Get(cacheKey)
-	if ok {
-		return cached.(string), nil
+
+	if entry, ok := backend.responseCache.Get(cacheKey); ok {
+		if time.Now().Before(entry.FreshUntil) {
+			return entry.Value, nil
+		}
+
+		go backend.refreshCached(cacheKey, remoteCommand, data, auth)
+		return entry.Value, nil
+	}
+
+	// The singleflight call is shared by every goroutine asking for cacheKey
+	// at once; it must not be tied to any single one of their contexts, or
+	// one caller disconnecting would cancel the backend call out from under
+	// every other caller still waiting on it. Use context.Background() here,
+	// same as refreshCached below.
+	result, err, _ := backend.reloadGroup.Do(cacheKey, func() (interface{}, error) {
+		return backend.SendRemoteCommand(context.Background(), remoteCommand, data, auth)
+	})
+	if err != nil {
+		return "", err
 	}
-	return backend.SendRemoteCommand(remoteCommand, data, auth)
+	return result.(string), nil
 }
 
-// SendRemoteCommand performs a RPC call on the backend by POSTing to `/cmd/$remoteCommand`.
+// refreshCached re-populates a stale cache entry in the background.
+//
+// It shares reloadGroup with SendRemoteCommandCached so a stale entry being
+// hit by many goroutines at once still only triggers one backend call.
+// The refresh runs detached from any request's context, since it may still
+// be in flight after the request that triggered it has finished.
+func (backend *backendInfo) refreshCached(cacheKey, remoteCommand, data string, auth AuthInfo) {
+	_, _, _ = backend.reloadGroup.Do(cacheKey, func() (interface{}, error) {
+		return backend.SendRemoteCommand(context.Background(), remoteCommand, data, auth)
+	})
+}
+
+// SendRemoteCommand performs a RPC call on the backend, by default POSTing
+// to `/cmd/$remoteCommand` (see BackendTransport for the gRPC alternative).
 //
 // The form data is as follows: `clientData` is the JSON in the `data` parameter
 // (should be retrieved from ClientMessage.Arguments), `username` is AuthInfo.TwitchUsername,
 // and `authenticated` is 1 or 0 depending on AuthInfo.UsernameValidated.
 //
-// 401 responses return an ErrAuthorizationNeeded.
+// Authorization failures return an ErrAuthorizationNeeded.
 //
-// Non-2xx responses return the response body as an error to the client (application/json
-// responses are sent as-is, non-json are sent as a JSON string).
+// Errors reported by the backend are returned as an ErrForwardedFromBackend.
 //
-// If a 2xx response has the FFZ-Cache header, its value is used as a minimum number of
-// seconds to cache the response for. (Responses may be cached for longer, see
+// If the backend reports a cache duration, the response is cached for at
+// least that long. (Responses may be cached for longer, see
 // SendRemoteCommandCached and the cache implementation.)
 //
-// A successful response updates the Statistics.Health.Backend map.
-func (backend *backendInfo) SendRemoteCommand(remoteCommand, data string, auth AuthInfo) (responseStr string, err error) {
-	destURL := fmt.Sprintf("%s/cmd/%s", backend.baseURL, remoteCommand)
-	healthBucket := fmt.Sprintf("/cmd/%s", remoteCommand)
-
-	formData := url.Values{
-		"clientData": []string{data},
-		"username":   []string{auth.TwitchUsername},
-	}
-
-	if auth.UsernameValidated {
-		formData.Set("authenticated", "1")
-	} else {
-		formData.Set("authenticated", "0")
-	}
-
-	sealedForm, err := backend.secureForm.Seal(formData)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := backend.HTTPClient.PostForm(destURL, sealedForm)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	respBytes, err := ioutil.ReadAll(resp.Body)
+// A successful response refreshes the endpoint's circuit breaker (see
+// backendEndpoint.recordResult), which is what Statistics.Health.Backend
+// reports through backendInfo.BackendHealth.
+//
+// ctx governs the lifetime of the backend call; callers on the websocket
+// goroutine should cancel it when the client disconnects instead of relying
+// on a single fixed timeout.
+func (backend *backendInfo) SendRemoteCommand(ctx context.Context, remoteCommand, data string, auth AuthInfo) (responseStr string, err error) {
+	var result commandResult
+	err = backend.withEndpoint(ctx, func(ep *backendEndpoint) (innerErr error) {
+		result, innerErr = ep.transport.SendCommand(ctx, remoteCommand, data, auth)
+		return innerErr
+	})
 	if err != nil {
 		return "", err
 	}
 
-	responseStr = string(respBytes)
-
-	if resp.StatusCode == 401 {
-		return "", ErrAuthorizationNeeded
-	} else if resp.StatusCode < 200 || resp.StatusCode > 299 { // any non-2xx
-		// If the Content-Type header includes a charset, ignore it.
-		// typeStr, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
-		// inline the part of the function we care about
-		typeStr := resp.Header.Get("Content-Type")
-		splitIdx := strings.IndexRune(typeStr, ';')
-		if splitIdx != -1 {
-			typeStr = strings.TrimSpace(typeStr[0:splitIdx])
-		}
-
-		if typeStr == "application/json" {
-			var err2 ErrForwardedFromBackend
-			err := json.Unmarshal(respBytes, &err2.JSONError)
-			if err != nil {
-				return "", fmt.Errorf("error decoding json error from backend: %v | %s", err, responseStr)
-			}
-			return "", err2
-		}
-		return "", httpError(resp.StatusCode)
-	}
-
-	if resp.Header.Get("FFZ-Cache") != "" {
-		durSecs, err := strconv.ParseInt(resp.Header.Get("FFZ-Cache"), 10, 64)
-		if err != nil {
-			return "", fmt.Errorf("The RPC server returned a non-integer cache duration: %v", err)
-		}
-		duration := time.Duration(durSecs) * time.Second
-		backend.responseCache.Set(
+	if result.CacheSeconds > 0 {
+		duration := time.Duration(result.CacheSeconds) * time.Second
+		backend.responseCache.SetWithTTL(
 			getCacheKey(remoteCommand, data),
-			responseStr,
-			duration,
+			cacheEntry{Value: result.Response, FreshUntil: time.Now().Add(duration)},
+			duration+staleCacheTTL,
 		)
 	}
 
-	now := time.Now().UTC()
-	backend.lastSuccessLock.Lock()
-	defer backend.lastSuccessLock.Unlock()
-	backend.lastSuccess[bPathOtherCommand] = now
-	backend.lastSuccess[healthBucket] = now
-
-	return
+	return result.Response, nil
 }
 
 // SendAggregatedData sends aggregated emote usage and following data to the backend server.
-func (backend *backendInfo) SendAggregatedData(form url.Values) error {
-	sealedForm, err := backend.secureForm.Seal(form)
-	if err != nil {
-		return err
-	}
-
-	resp, err := backend.HTTPClient.PostForm(backend.postStatisticsURL, sealedForm)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		resp.Body.Close()
-		return httpError(resp.StatusCode)
-	}
-
-	backend.lastSuccessLock.Lock()
-	defer backend.lastSuccessLock.Unlock()
-	backend.lastSuccess[bPathAggStats] = time.Now().UTC()
-
-	return resp.Body.Close()
+func (backend *backendInfo) SendAggregatedData(ctx context.Context, form url.Values) error {
+	return backend.withEndpoint(ctx, func(ep *backendEndpoint) error {
+		return ep.transport.PostStatistics(ctx, form)
+	})
 }
 
 // ErrBackendNotOK indicates that the backend replied with something other than the string "ok".
@@ -241,55 +211,78 @@ func (noe ErrBackendNotOK) Error() string {
 // POST data:
 // channels=room.trihex
 // added=t
-func (backend *backendInfo) SendNewTopicNotice(topic string) error {
-	return backend.sendTopicNotice(topic, true)
+//
+// With N socketserver nodes behind a load balancer, most subscriptions to an
+// already-popular topic are only new to this node, not to the fleet. topic's
+// cluster-wide subscriber count (backend.clusterBus) is incremented first,
+// and the backend is only notified if this node's subscription is the
+// fleet's first — i.e. no other node needs to tell it again.
+func (backend *backendInfo) SendNewTopicNotice(ctx context.Context, topic string) error {
+	firstSubscriber, err := backend.clusterBus.PublishSubscribe(ctx, topic)
+	if err != nil {
+		return err
+	}
+	if !firstSubscriber {
+		return nil
+	}
+
+	return backend.withEndpoint(ctx, func(ep *backendEndpoint) error {
+		return ep.transport.AddTopic(ctx, []string{topic})
+	})
 }
 
 // SendCleanupTopicsNotice notifies the backend that pub/sub topics have no subscribers anymore.
 // POST data:
 // channels=room.sirstendec,room.bobross,feature.foo
 // added=f
-func (backend *backendInfo) SendCleanupTopicsNotice(topics []string) error {
-	return backend.sendTopicNotice(strings.Join(topics, ","), false)
-}
-
-func (backend *backendInfo) sendTopicNotice(topic string, added bool) error {
-	formData := url.Values{}
-	formData.Set("channels", topic)
-	if added {
-		formData.Set("added", "t")
-	} else {
-		formData.Set("added", "f")
+//
+// Each topic's cluster-wide subscriber count is decremented first; only the
+// topics whose count reaches zero fleet-wide (meaning no other node still
+// has a subscriber) are actually reported to the backend. There is no
+// leader election for this: whichever node's decrement happens to cross the
+// 0 boundary is the one that reports it.
+func (backend *backendInfo) SendCleanupTopicsNotice(ctx context.Context, topics []string) error {
+	var toRemove []string
+	for _, topic := range topics {
+		lastSubscriber, err := backend.clusterBus.PublishUnsubscribe(ctx, topic)
+		if err != nil {
+			return err
+		}
+		if lastSubscriber {
+			toRemove = append(toRemove, topic)
+		}
 	}
-
-	sealedForm, err := backend.secureForm.Seal(formData)
-	if err != nil {
-		return err
+	if len(toRemove) == 0 {
+		return nil
 	}
 
-	resp, err := backend.HTTPClient.PostForm(backend.addTopicURL, sealedForm)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return backend.withEndpoint(ctx, func(ep *backendEndpoint) error {
+		return ep.transport.RemoveTopics(ctx, toRemove)
+	})
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		respBytes, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return ErrBackendNotOK{Code: resp.StatusCode, Response: fmt.Sprintf("(error reading non-2xx response): %s", err.Error())}
-		}
-		return ErrBackendNotOK{Code: resp.StatusCode, Response: string(respBytes)}
-	}
+// AnnounceStartup tells the backend that this socketserver node has come
+// online, running the given version and ServerID.
+func (backend *backendInfo) AnnounceStartup(ctx context.Context, version string, serverID int) error {
+	return backend.withEndpoint(ctx, func(ep *backendEndpoint) error {
+		return ep.transport.AnnounceStartup(ctx, version, serverID)
+	})
+}
 
-	backend.lastSuccessLock.Lock()
-	defer backend.lastSuccessLock.Unlock()
-	backend.lastSuccess[bPathAddTopic] = time.Now().UTC()
+// httpStatusError is returned by the HTTP transport for a non-2xx response
+// that isn't otherwise translated into a more specific error. Its StatusCode
+// is used by isRetriableBackendError to decide whether a 5xx is worth
+// retrying against another backend endpoint.
+type httpStatusError struct {
+	StatusCode int
+}
 
-	return nil
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("backend http error: %d", e.StatusCode)
 }
 
 func httpError(statusCode int) error {
-	return fmt.Errorf("backend http error: %d", statusCode)
+	return httpStatusError{StatusCode: statusCode}
 }
 
 // GenerateKeys generates a new NaCl keypair for the server and writes out the default configuration file.
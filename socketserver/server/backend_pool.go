@@ -0,0 +1,347 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/FrankerFaceZ/FrankerFaceZ/socketserver/server/naclform"
+	"golang.org/x/crypto/nacl/box"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultMaxRetries       = 2
+	defaultRetryBaseDelay   = 200 * time.Millisecond
+)
+
+// breakerState is one of the three states of a backendEndpoint's circuit
+// breaker: closed (normal), open (failing fast), or half-open (a single
+// probe request is allowed through to test recovery).
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// backendEndpoint is one member of backendInfo's pool of backend servers.
+// Each endpoint has its own base URL (or gRPC address), its own precomputed
+// NaCl shared key (since every backend may have a distinct keypair), and its
+// own circuit breaker, since one backend going bad shouldn't trip the
+// breaker for its siblings.
+type backendEndpoint struct {
+	ID        string
+	BaseURL   string
+	GRPCAddr  string
+	PublicKey []byte
+
+	secureForm naclform.ServerInfo
+	transport  BackendTransport
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	mu               sync.Mutex
+	state            breakerState
+	failureCount     int
+	openedAt         time.Time
+	halfOpenInFlight bool
+	lastSuccess      time.Time
+}
+
+func newBackendEndpoint(config *ConfigFile, id, baseURL, grpcAddr string, publicKey []byte) (*backendEndpoint, error) {
+	ep := &backendEndpoint{
+		ID:               id,
+		BaseURL:          baseURL,
+		GRPCAddr:         grpcAddr,
+		PublicKey:        publicKey,
+		breakerThreshold: config.BackendBreakerThreshold,
+		breakerCooldown:  config.BackendBreakerCooldown,
+	}
+	if ep.breakerThreshold <= 0 {
+		ep.breakerThreshold = defaultBreakerThreshold
+	}
+	if ep.breakerCooldown <= 0 {
+		ep.breakerCooldown = defaultBreakerCooldown
+	}
+
+	ep.secureForm.ServerID = config.ServerID
+
+	var theirPublic, ourPrivate [32]byte
+	copy(theirPublic[:], publicKey)
+	copy(ourPrivate[:], config.OurPrivateKey)
+	box.Precompute(&ep.secureForm.SharedKey, &theirPublic, &ourPrivate)
+
+	transport, err := newBackendTransport(config, baseURL, grpcAddr, &ep.secureForm)
+	if err != nil {
+		return nil, fmt.Errorf("backend endpoint %q: %w", id, err)
+	}
+	ep.transport = transport
+
+	return ep, nil
+}
+
+// allowRequest reports whether a call may be attempted against this
+// endpoint right now, given its circuit breaker state. Transitioning from
+// open to half-open (once the cooldown has elapsed) and reserving the
+// single half-open probe both happen here, as a side effect of the check.
+func (ep *backendEndpoint) allowRequest() bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	switch ep.state {
+	case breakerClosed:
+		return true
+	case breakerOpen:
+		if time.Since(ep.openedAt) < ep.breakerCooldown {
+			return false
+		}
+		ep.state = breakerHalfOpen
+		ep.halfOpenInFlight = true
+		return true
+	case breakerHalfOpen:
+		if ep.halfOpenInFlight {
+			return false
+		}
+		ep.halfOpenInFlight = true
+		return true
+	}
+	return true
+}
+
+// recordResult updates the endpoint's breaker state and last-success time
+// based on the outcome of a call that allowRequest permitted.
+//
+// Only errors that isRetriableBackendError considers infrastructure-shaped
+// (network errors, 5xx, timeouts) count against the breaker. An application-
+// level error (bad auth, a 4xx, a JSON error forwarded from the backend)
+// means the backend itself answered fine, so it must not push a healthy
+// endpoint toward breakerOpen.
+func (ep *backendEndpoint) recordResult(err error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if err == nil {
+		ep.state = breakerClosed
+		ep.failureCount = 0
+		ep.halfOpenInFlight = false
+		ep.lastSuccess = time.Now().UTC()
+		return
+	}
+
+	if !isRetriableBackendError(err) {
+		if ep.state == breakerHalfOpen {
+			// The probe endpoint answered, just with an application-level
+			// error; that's evidence it's back up, so close the breaker.
+			ep.state = breakerClosed
+			ep.failureCount = 0
+			ep.halfOpenInFlight = false
+		}
+		return
+	}
+
+	if ep.state == breakerHalfOpen {
+		// The probe failed; stay open for another cooldown period.
+		ep.state = breakerOpen
+		ep.openedAt = time.Now()
+		ep.halfOpenInFlight = false
+		return
+	}
+
+	ep.failureCount++
+	if ep.failureCount >= ep.breakerThreshold {
+		ep.state = breakerOpen
+		ep.openedAt = time.Now()
+	}
+}
+
+func (ep *backendEndpoint) isHealthy() bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.state != breakerOpen
+}
+
+func (ep *backendEndpoint) getLastSuccess() time.Time {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.lastSuccess
+}
+
+// backoffWithJitter returns a delay in [base*2^attempt/2, base*2^attempt],
+// for the attempt'th retry (attempt 0 is the first retry, i.e. the second
+// overall try).
+func backoffWithJitter(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	maxDelay := base << uint(attempt)
+	if maxDelay <= 0 { // overflow guard for a very large attempt count
+		maxDelay = base
+	}
+	half := maxDelay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// orderedEndpoints returns every pool member starting from the next
+// round-robin position, with healthy endpoints sorted ahead of unhealthy
+// ones, so a caller retrying on failure exhausts healthy endpoints first.
+func (backend *backendInfo) orderedEndpoints() ([]*backendEndpoint, error) {
+	backend.endpointsMu.RLock()
+	eps := make([]*backendEndpoint, len(backend.endpoints))
+	copy(eps, backend.endpoints)
+	backend.endpointsMu.RUnlock()
+
+	if len(eps) == 0 {
+		return nil, errNoBackendEndpoints
+	}
+
+	start := int(atomic.AddUint64(&backend.nextEndpoint, 1) % uint64(len(eps)))
+	ordered := make([]*backendEndpoint, len(eps))
+	for i := range eps {
+		ordered[i] = eps[(start+i)%len(eps)]
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].isHealthy() && !ordered[j].isHealthy()
+	})
+
+	return ordered, nil
+}
+
+var errNoBackendEndpoints = fmt.Errorf("no backend endpoints configured")
+
+// ErrBackendUnavailable is returned when every endpoint willing to take a
+// request has its circuit breaker open.
+var ErrBackendUnavailable = fmt.Errorf("backend unavailable: circuit breaker open")
+
+// withEndpoint runs fn against the pool in round-robin order, retrying on
+// the next endpoint (after an exponential backoff with jitter) whenever
+// fn's error is retriable (see isRetriableBackendError), up to
+// config.BackendMaxRetries retries. An endpoint whose circuit breaker is
+// open is skipped for free — no backoff sleep, and it doesn't count against
+// the retry budget — so an outage fails fast instead of blocking the
+// caller for the whole backoff schedule. If every endpoint is unavailable,
+// ErrBackendUnavailable is returned.
+func (backend *backendInfo) withEndpoint(ctx context.Context, fn func(ep *backendEndpoint) error) error {
+	eps, err := backend.orderedEndpoints()
+	if err != nil {
+		return err
+	}
+
+	maxRetries := backend.config.BackendMaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	var lastErr error = ErrBackendUnavailable
+	attempt := 0
+	// epIdx cycles through eps independently of attempt, so a skipped
+	// (breaker-open) endpoint never consumes a slot of the retry budget;
+	// it's bounded by len(eps)*(maxRetries+1) purely so an all-open pool
+	// can't spin this loop forever.
+	maxSkips := len(eps) * (maxRetries + 1)
+	for epIdx := 0; attempt <= maxRetries && epIdx < maxSkips; epIdx++ {
+		ep := eps[epIdx%len(eps)]
+		if !ep.allowRequest() {
+			continue
+		}
+
+		if attempt > 0 {
+			delay := backoffWithJitter(backend.config.BackendRetryBaseDelay, attempt-1)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		lastErr = fn(ep)
+		ep.recordResult(lastErr)
+		if lastErr == nil || !isRetriableBackendError(lastErr) {
+			return lastErr
+		}
+		attempt++
+	}
+	return lastErr
+}
+
+// AddBackendEndpoint adds a new backend to the pool without a restart, for
+// use by the admin HTTP surface.
+func (backend *backendInfo) AddBackendEndpoint(id, baseURL, grpcAddr string, publicKey []byte) error {
+	backend.endpointsMu.Lock()
+	defer backend.endpointsMu.Unlock()
+
+	for _, existing := range backend.endpoints {
+		if existing.ID == id {
+			return fmt.Errorf("backend endpoint %q already exists", id)
+		}
+	}
+
+	ep, err := newBackendEndpoint(backend.config, id, baseURL, grpcAddr, publicKey)
+	if err != nil {
+		return err
+	}
+
+	backend.endpoints = append(backend.endpoints, ep)
+	return nil
+}
+
+// RemoveBackendEndpoint drains a backend out of the pool without a restart,
+// for use by the admin HTTP surface. In-flight calls to it are unaffected;
+// it simply won't be picked for new ones.
+func (backend *backendInfo) RemoveBackendEndpoint(id string) error {
+	backend.endpointsMu.Lock()
+	defer backend.endpointsMu.Unlock()
+
+	for i, ep := range backend.endpoints {
+		if ep.ID == id {
+			backend.endpoints = append(backend.endpoints[:i:i], backend.endpoints[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such backend endpoint %q", id)
+}
+
+// BackendHealth returns the last successful call time for every endpoint in
+// the pool, keyed by endpoint ID, for Statistics.Health.Backend.
+func (backend *backendInfo) BackendHealth() map[string]time.Time {
+	backend.endpointsMu.RLock()
+	eps := make([]*backendEndpoint, len(backend.endpoints))
+	copy(eps, backend.endpoints)
+	backend.endpointsMu.RUnlock()
+
+	health := make(map[string]time.Time, len(eps))
+	for _, ep := range eps {
+		health[ep.ID] = ep.getLastSuccess()
+	}
+	return health
+}
+
+// isRetriableBackendError decides whether withEndpoint should try the next
+// endpoint or give up immediately. Application-level errors forwarded from
+// the backend (bad auth, a JSON error body) mean every endpoint would give
+// the same answer, so only infrastructure-shaped failures are retried.
+func isRetriableBackendError(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch e := err.(type) {
+	case ErrForwardedFromBackend:
+		return false
+	case httpStatusError:
+		return e.StatusCode >= 500
+	case ErrBackendNotOK:
+		return e.Code >= 500
+	}
+	if err == ErrAuthorizationNeeded {
+		return false
+	}
+	return true
+}
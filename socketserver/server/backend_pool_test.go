@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errSentinel = errors.New("sentinel test error")
+
+func TestBackendEndpointBreakerOpensAfterThreshold(t *testing.T) {
+	ep := &backendEndpoint{breakerThreshold: 3, breakerCooldown: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		if !ep.allowRequest() {
+			t.Fatalf("request %d: expected closed breaker to allow the request", i)
+		}
+		ep.recordResult(errSentinel)
+	}
+	if !ep.isHealthy() {
+		t.Fatal("breaker should still be closed after 2 of 3 failures")
+	}
+
+	if !ep.allowRequest() {
+		t.Fatal("3rd request: expected closed breaker to allow the request")
+	}
+	ep.recordResult(errSentinel)
+
+	if ep.isHealthy() {
+		t.Fatal("breaker should be open once failureCount reaches breakerThreshold")
+	}
+	if ep.allowRequest() {
+		t.Fatal("open breaker should not allow a request before the cooldown elapses")
+	}
+}
+
+func TestBackendEndpointBreakerHalfOpenProbeSucceeds(t *testing.T) {
+	ep := &backendEndpoint{breakerThreshold: 1, breakerCooldown: time.Millisecond}
+
+	ep.allowRequest()
+	ep.recordResult(errSentinel) // opens the breaker
+
+	time.Sleep(2 * time.Millisecond)
+
+	if !ep.allowRequest() {
+		t.Fatal("breaker should allow a single half-open probe after the cooldown elapses")
+	}
+	if ep.allowRequest() {
+		t.Fatal("breaker should not allow a second concurrent half-open probe")
+	}
+
+	ep.recordResult(nil) // the probe succeeds
+	if !ep.isHealthy() {
+		t.Fatal("a successful half-open probe should close the breaker")
+	}
+	if !ep.allowRequest() {
+		t.Fatal("closed breaker should allow requests again")
+	}
+}
+
+func TestBackendEndpointBreakerHalfOpenProbeFailureReopens(t *testing.T) {
+	ep := &backendEndpoint{breakerThreshold: 1, breakerCooldown: time.Millisecond}
+
+	ep.allowRequest()
+	ep.recordResult(errSentinel) // opens the breaker
+
+	time.Sleep(2 * time.Millisecond)
+	ep.allowRequest()            // half-open probe
+	ep.recordResult(errSentinel) // the probe fails
+
+	if ep.isHealthy() {
+		t.Fatal("a failed half-open probe should reopen the breaker")
+	}
+	if ep.allowRequest() {
+		t.Fatal("a freshly reopened breaker should not allow another request immediately")
+	}
+}
+
+// TestBackendEndpointBreakerIgnoresNonRetriableErrors guards against an
+// ordinary application-level error (e.g. an unauthenticated client calling a
+// command that requires auth) tripping the breaker for every other caller,
+// even though the backend itself answered and is perfectly healthy.
+func TestBackendEndpointBreakerIgnoresNonRetriableErrors(t *testing.T) {
+	ep := &backendEndpoint{breakerThreshold: 1, breakerCooldown: time.Minute}
+
+	for i := 0; i < 5; i++ {
+		if !ep.allowRequest() {
+			t.Fatalf("request %d: closed breaker should allow the request", i)
+		}
+		ep.recordResult(ErrAuthorizationNeeded)
+	}
+
+	if !ep.isHealthy() {
+		t.Fatal("non-retriable application errors must not open the breaker")
+	}
+	if ep.failureCount != 0 {
+		t.Fatalf("failureCount = %d, want 0 for non-retriable errors", ep.failureCount)
+	}
+}
+
+// TestBackendEndpointBreakerHalfOpenProbeNonRetriableCloses guards against a
+// half-open probe that comes back with an application-level error being
+// mistaken for an infrastructure failure: the backend answered, so the
+// breaker should close instead of reopening.
+func TestBackendEndpointBreakerHalfOpenProbeNonRetriableCloses(t *testing.T) {
+	ep := &backendEndpoint{breakerThreshold: 1, breakerCooldown: time.Millisecond}
+
+	ep.allowRequest()
+	ep.recordResult(errSentinel) // opens the breaker
+
+	time.Sleep(2 * time.Millisecond)
+	ep.allowRequest() // half-open probe
+	ep.recordResult(ErrAuthorizationNeeded)
+
+	if !ep.isHealthy() {
+		t.Fatal("a half-open probe answered with a non-retriable error should close the breaker")
+	}
+}
+
+// TestOrderedEndpointsRoundRobinsAndSortsHealthyFirst checks both of
+// orderedEndpoints' jobs: each call starts from the next round-robin
+// position, and within that rotation, healthy endpoints sort ahead of ones
+// whose breaker is open.
+func TestOrderedEndpointsRoundRobinsAndSortsHealthyFirst(t *testing.T) {
+	a := &backendEndpoint{ID: "a", breakerThreshold: 1, breakerCooldown: time.Hour}
+	b := &backendEndpoint{ID: "b", breakerThreshold: 1, breakerCooldown: time.Hour}
+	c := &backendEndpoint{ID: "c", breakerThreshold: 1, breakerCooldown: time.Hour}
+	b.allowRequest()
+	b.recordResult(errSentinel) // opens b's breaker
+
+	backend := &backendInfo{endpoints: []*backendEndpoint{a, b, c}}
+
+	first, err := backend.orderedEndpoints()
+	if err != nil {
+		t.Fatalf("orderedEndpoints: %v", err)
+	}
+	second, err := backend.orderedEndpoints()
+	if err != nil {
+		t.Fatalf("orderedEndpoints: %v", err)
+	}
+
+	if first[0].ID == second[0].ID {
+		t.Fatalf("consecutive calls should start from different round-robin positions, both started at %q", first[0].ID)
+	}
+	for _, ordered := range [][]*backendEndpoint{first, second} {
+		if ordered[len(ordered)-1].ID != "b" {
+			t.Fatalf("unhealthy endpoint %q should sort last, got order %v", "b", []string{ordered[0].ID, ordered[1].ID, ordered[2].ID})
+		}
+	}
+}
+
+func TestOrderedEndpointsNoneConfiguredReturnsError(t *testing.T) {
+	backend := &backendInfo{}
+	if _, err := backend.orderedEndpoints(); err != errNoBackendEndpoints {
+		t.Fatalf("expected errNoBackendEndpoints, got %v", err)
+	}
+}
+
+// TestWithEndpointSkipsOpenBreakerWithoutSleeping guards against
+// withEndpoint consuming a backoff sleep for an endpoint it only skipped
+// (breaker open), rather than one it actually tried and failed.
+func TestWithEndpointSkipsOpenBreakerWithoutSleeping(t *testing.T) {
+	ep := &backendEndpoint{breakerThreshold: 1, breakerCooldown: time.Hour}
+	ep.allowRequest()
+	ep.recordResult(errSentinel) // opens the breaker, cooldown far in the future
+
+	backend := &backendInfo{
+		config:    &ConfigFile{BackendMaxRetries: 2, BackendRetryBaseDelay: time.Second},
+		endpoints: []*backendEndpoint{ep},
+	}
+
+	called := false
+	start := time.Now()
+	err := backend.withEndpoint(context.Background(), func(ep *backendEndpoint) error {
+		called = true
+		return nil
+	})
+	elapsed := time.Since(start)
+
+	if called {
+		t.Fatal("fn should never be called when the only endpoint's breaker is open")
+	}
+	if err != ErrBackendUnavailable {
+		t.Fatalf("expected ErrBackendUnavailable, got %v", err)
+	}
+	if elapsed > 100*time.Millisecond {
+		t.Fatalf("withEndpoint should fail fast on an all-open pool, took %v", elapsed)
+	}
+}
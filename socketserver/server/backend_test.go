@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingBackendTransport is a BackendTransport whose SendCommand counts
+// calls and blocks on release (if set) before returning, so tests can pin
+// down exactly how many backend round-trips a batch of callers triggers.
+type countingBackendTransport struct {
+	calls   int32
+	release chan struct{}
+	result  commandResult
+}
+
+func (t *countingBackendTransport) SendCommand(ctx context.Context, remoteCommand, data string, auth AuthInfo) (commandResult, error) {
+	atomic.AddInt32(&t.calls, 1)
+	if t.release != nil {
+		<-t.release
+	}
+	return t.result, nil
+}
+
+func (t *countingBackendTransport) AnnounceStartup(ctx context.Context, version string, serverID int) error {
+	return nil
+}
+func (t *countingBackendTransport) AddTopic(ctx context.Context, channels []string) error { return nil }
+func (t *countingBackendTransport) RemoveTopics(ctx context.Context, channels []string) error {
+	return nil
+}
+func (t *countingBackendTransport) PostStatistics(ctx context.Context, form url.Values) error {
+	return nil
+}
+
+func newTestBackend(transport BackendTransport) *backendInfo {
+	ep := &backendEndpoint{ID: "default", breakerThreshold: defaultBreakerThreshold, breakerCooldown: defaultBreakerCooldown, transport: transport}
+	return &backendInfo{
+		config:        &ConfigFile{},
+		responseCache: newMemoryResponseCache(),
+		endpoints:     []*backendEndpoint{ep},
+	}
+}
+
+// TestSendRemoteCommandCachedDedupesConcurrentCallers checks that a burst of
+// callers asking for the same remoteCommand/data while nothing is cached yet
+// collapses into a single backend round-trip, with every caller getting that
+// one call's response.
+func TestSendRemoteCommandCachedDedupesConcurrentCallers(t *testing.T) {
+	transport := &countingBackendTransport{
+		release: make(chan struct{}),
+		result:  commandResult{Response: "the one true response"},
+	}
+	backend := newTestBackend(transport)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = backend.SendRemoteCommandCached(context.Background(), "some_command", "data", AuthInfo{})
+		}(i)
+	}
+
+	// Give every goroutine a chance to reach reloadGroup.Do before letting
+	// the single in-flight call complete.
+	time.Sleep(20 * time.Millisecond)
+	close(transport.release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&transport.calls); got != 1 {
+		t.Fatalf("backend transport called %d times, want 1", got)
+	}
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: unexpected error %v", i, errs[i])
+		}
+		if results[i] != "the one true response" {
+			t.Fatalf("caller %d: got %q, want %q", i, results[i], "the one true response")
+		}
+	}
+}
+
+// TestSendRemoteCommandCachedServesStaleWhileRefreshing checks that a stale
+// (but not yet evicted) cache entry is returned immediately, without waiting
+// on the backend, while a refresh happens in the background.
+func TestSendRemoteCommandCachedServesStaleWhileRefreshing(t *testing.T) {
+	transport := &countingBackendTransport{
+		result: commandResult{Response: "fresh response", CacheSeconds: 60},
+	}
+	backend := newTestBackend(transport)
+
+	cacheKey := getCacheKey("some_command", "data")
+	backend.responseCache.SetWithTTL(cacheKey, cacheEntry{
+		Value:      "stale response",
+		FreshUntil: time.Now().Add(-time.Minute),
+	}, staleCacheTTL)
+
+	start := time.Now()
+	result, err := backend.SendRemoteCommandCached(context.Background(), "some_command", "data", AuthInfo{})
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "stale response" {
+		t.Fatalf("got %q, want the stale cached value %q", result, "stale response")
+	}
+	if elapsed > 50*time.Millisecond {
+		t.Fatalf("SendRemoteCommandCached should return the stale value immediately, took %v", elapsed)
+	}
+
+	// The refresh is kicked off in a goroutine; wait for it to land.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if atomic.LoadInt32(&transport.calls) >= 1 {
+			if entry, ok := backend.responseCache.Get(cacheKey); ok && entry.Value == "fresh response" {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh never called the backend transport and updated the cache")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	entry, ok := backend.responseCache.Get(cacheKey)
+	if !ok {
+		t.Fatal("refreshed entry missing from cache")
+	}
+	if entry.Value != "fresh response" {
+		t.Fatalf("cache entry = %q after refresh, want %q", entry.Value, "fresh response")
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/FrankerFaceZ/FrankerFaceZ/socketserver/server/naclform"
+)
+
+// commandResult is what a BackendTransport returns for a successful
+// SendCommand call. CacheSeconds mirrors the old FFZ-Cache header: 0 means
+// "do not cache this response".
+type commandResult struct {
+	Response     string
+	CacheSeconds int64
+}
+
+// BackendTransport is how backendInfo actually talks to the backend server.
+//
+// The original (and default) implementation POSTs NaCl-sealed form data over
+// HTTP; httpBackendTransport implements that. grpcBackendTransport speaks
+// gRPC + protobuf instead, for deployments that prefer mTLS and a typed
+// wire format over hand-rolled form encoding.
+type BackendTransport interface {
+	// SendCommand is the transport for SendRemoteCommand.
+	//
+	// Authorization and JSON-error signals are returned as ErrAuthorizationNeeded
+	// and ErrForwardedFromBackend respectively, same as the HTTP implementation
+	// has always returned them to callers.
+	SendCommand(ctx context.Context, remoteCommand, data string, auth AuthInfo) (commandResult, error)
+
+	// AnnounceStartup is the transport for announcing server startup to the backend.
+	AnnounceStartup(ctx context.Context, version string, serverID int) error
+
+	// AddTopic is the transport for SendNewTopicNotice.
+	AddTopic(ctx context.Context, channels []string) error
+
+	// RemoveTopics is the transport for SendCleanupTopicsNotice.
+	RemoveTopics(ctx context.Context, channels []string) error
+
+	// PostStatistics is the transport for SendAggregatedData.
+	PostStatistics(ctx context.Context, form url.Values) error
+}
+
+// newBackendTransport builds the BackendTransport selected by
+// config.BackendTransport ("http", the default, or "grpc") for a single
+// backend endpoint. baseURL and grpcAddr are per-endpoint; mTLS settings for
+// the gRPC path are shared across every endpoint via config.
+func newBackendTransport(config *ConfigFile, baseURL, grpcAddr string, secureForm *naclform.ServerInfo) (BackendTransport, error) {
+	switch config.BackendTransport {
+	case "", "http":
+		return newHTTPBackendTransport(baseURL, secureForm), nil
+	case "grpc":
+		return newGRPCBackendTransport(config, grpcAddr)
+	default:
+		return nil, fmt.Errorf("unknown BackendTransport %q", config.BackendTransport)
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FrankerFaceZ/FrankerFaceZ/socketserver/server/naclform"
+)
+
+// httpBackendTransport is the original BackendTransport: NaCl-sealed form
+// data POSTed to the backend's REST endpoints.
+type httpBackendTransport struct {
+	httpClient http.Client
+	secureForm *naclform.ServerInfo
+
+	baseURL            string
+	announceStartupURL string
+	addTopicURL        string
+	postStatisticsURL  string
+}
+
+func newHTTPBackendTransport(baseURL string, secureForm *naclform.ServerInfo) *httpBackendTransport {
+	t := &httpBackendTransport{secureForm: secureForm, baseURL: baseURL}
+	t.httpClient.Timeout = 60 * time.Second
+	t.announceStartupURL = fmt.Sprintf("%s%s", t.baseURL, bPathAnnounceStartup)
+	t.addTopicURL = fmt.Sprintf("%s%s", t.baseURL, bPathAddTopic)
+	t.postStatisticsURL = fmt.Sprintf("%s%s", t.baseURL, bPathAggStats)
+	return t
+}
+
+func (t *httpBackendTransport) postSealedForm(ctx context.Context, destURL string, formData url.Values) (*http.Response, error) {
+	sealedForm, err := t.secureForm.Seal(formData)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, destURL, strings.NewReader(sealedForm.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return t.httpClient.Do(req)
+}
+
+func (t *httpBackendTransport) SendCommand(ctx context.Context, remoteCommand, data string, auth AuthInfo) (commandResult, error) {
+	destURL := fmt.Sprintf("%s/cmd/%s", t.baseURL, remoteCommand)
+
+	formData := url.Values{
+		"clientData": []string{data},
+		"username":   []string{auth.TwitchUsername},
+	}
+	if auth.UsernameValidated {
+		formData.Set("authenticated", "1")
+	} else {
+		formData.Set("authenticated", "0")
+	}
+
+	resp, err := t.postSealedForm(ctx, destURL, formData)
+	if err != nil {
+		return commandResult{}, err
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return commandResult{}, err
+	}
+	responseStr := string(respBytes)
+
+	if resp.StatusCode == 401 {
+		return commandResult{}, ErrAuthorizationNeeded
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 { // any non-2xx
+		// If the Content-Type header includes a charset, ignore it.
+		// typeStr, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		// inline the part of the function we care about
+		typeStr := resp.Header.Get("Content-Type")
+		splitIdx := strings.IndexRune(typeStr, ';')
+		if splitIdx != -1 {
+			typeStr = strings.TrimSpace(typeStr[0:splitIdx])
+		}
+
+		if typeStr == "application/json" {
+			var err2 ErrForwardedFromBackend
+			if err := json.Unmarshal(respBytes, &err2.JSONError); err != nil {
+				return commandResult{}, fmt.Errorf("error decoding json error from backend: %v | %s", err, responseStr)
+			}
+			return commandResult{}, err2
+		}
+		return commandResult{}, httpError(resp.StatusCode)
+	}
+
+	var cacheSeconds int64
+	if cacheHeader := resp.Header.Get("FFZ-Cache"); cacheHeader != "" {
+		cacheSeconds, err = strconv.ParseInt(cacheHeader, 10, 64)
+		if err != nil {
+			return commandResult{}, fmt.Errorf("The RPC server returned a non-integer cache duration: %v", err)
+		}
+	}
+
+	return commandResult{Response: responseStr, CacheSeconds: cacheSeconds}, nil
+}
+
+func (t *httpBackendTransport) AnnounceStartup(ctx context.Context, version string, serverID int) error {
+	formData := url.Values{}
+	formData.Set("version", version)
+	formData.Set("server_id", strconv.Itoa(serverID))
+
+	resp, err := t.postSealedForm(ctx, t.announceStartupURL, formData)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return httpError(resp.StatusCode)
+	}
+	return nil
+}
+
+func (t *httpBackendTransport) AddTopic(ctx context.Context, channels []string) error {
+	return t.sendTopicNotice(ctx, channels, true)
+}
+
+func (t *httpBackendTransport) RemoveTopics(ctx context.Context, channels []string) error {
+	return t.sendTopicNotice(ctx, channels, false)
+}
+
+func (t *httpBackendTransport) sendTopicNotice(ctx context.Context, channels []string, added bool) error {
+	formData := url.Values{}
+	formData.Set("channels", strings.Join(channels, ","))
+	if added {
+		formData.Set("added", "t")
+	} else {
+		formData.Set("added", "f")
+	}
+
+	resp, err := t.postSealedForm(ctx, t.addTopicURL, formData)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return ErrBackendNotOK{Code: resp.StatusCode, Response: fmt.Sprintf("(error reading non-2xx response): %s", err.Error())}
+		}
+		return ErrBackendNotOK{Code: resp.StatusCode, Response: string(respBytes)}
+	}
+
+	return nil
+}
+
+func (t *httpBackendTransport) PostStatistics(ctx context.Context, form url.Values) error {
+	resp, err := t.postSealedForm(ctx, t.postStatisticsURL, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return httpError(resp.StatusCode)
+	}
+	return nil
+}
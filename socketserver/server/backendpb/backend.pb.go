@@ -0,0 +1,182 @@
+// Package backendpb mirrors the shape protoc-gen-go would produce for
+// backend.proto, but is hand-maintained: the build has no protoc/buf step,
+// so these messages and their protoc-gen-go-grpc counterparts in
+// backend_grpc.pb.go are kept in sync with backend.proto by hand. See the
+// comment below on why Reset/String/ProtoMessage are enough to satisfy
+// grpc-go without a real generated FileDescriptorProto.
+package backendpb
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// Reset, String, and ProtoMessage on every message below satisfy the
+// classic protoc-gen-go v1 proto.Message interface. grpc-go's default codec
+// accepts a v1 message (adapting it to protoreflect internally via its
+// struct tags), so these three methods are all a message needs to
+// round-trip correctly over gRPC without a compiled FileDescriptorProto or
+// a hand-rolled ProtoReflect implementation.
+
+type SendCommandRequest struct {
+	RemoteCommand     string `protobuf:"bytes,1,opt,name=remote_command,json=remoteCommand,proto3" json:"remote_command,omitempty"`
+	ClientData        string `protobuf:"bytes,2,opt,name=client_data,json=clientData,proto3" json:"client_data,omitempty"`
+	TwitchUsername    string `protobuf:"bytes,3,opt,name=twitch_username,json=twitchUsername,proto3" json:"twitch_username,omitempty"`
+	UsernameValidated bool   `protobuf:"varint,4,opt,name=username_validated,json=usernameValidated,proto3" json:"username_validated,omitempty"`
+}
+
+func (m *SendCommandRequest) Reset()         { *m = SendCommandRequest{} }
+func (m *SendCommandRequest) String() string { return proto.CompactTextString(m) }
+func (*SendCommandRequest) ProtoMessage()    {}
+
+func (m *SendCommandRequest) GetRemoteCommand() string {
+	if m != nil {
+		return m.RemoteCommand
+	}
+	return ""
+}
+
+func (m *SendCommandRequest) GetClientData() string {
+	if m != nil {
+		return m.ClientData
+	}
+	return ""
+}
+
+func (m *SendCommandRequest) GetTwitchUsername() string {
+	if m != nil {
+		return m.TwitchUsername
+	}
+	return ""
+}
+
+func (m *SendCommandRequest) GetUsernameValidated() bool {
+	if m != nil {
+		return m.UsernameValidated
+	}
+	return false
+}
+
+type SendCommandResponse struct {
+	AuthorizationRequired bool   `protobuf:"varint,1,opt,name=authorization_required,json=authorizationRequired,proto3" json:"authorization_required,omitempty"`
+	ResponseJson          string `protobuf:"bytes,2,opt,name=response_json,json=responseJson,proto3" json:"response_json,omitempty"`
+	ErrorJson             string `protobuf:"bytes,3,opt,name=error_json,json=errorJson,proto3" json:"error_json,omitempty"`
+	CacheSeconds          int64  `protobuf:"varint,4,opt,name=cache_seconds,json=cacheSeconds,proto3" json:"cache_seconds,omitempty"`
+}
+
+func (m *SendCommandResponse) Reset()         { *m = SendCommandResponse{} }
+func (m *SendCommandResponse) String() string { return proto.CompactTextString(m) }
+func (*SendCommandResponse) ProtoMessage()    {}
+
+func (m *SendCommandResponse) GetAuthorizationRequired() bool {
+	if m != nil {
+		return m.AuthorizationRequired
+	}
+	return false
+}
+
+func (m *SendCommandResponse) GetResponseJson() string {
+	if m != nil {
+		return m.ResponseJson
+	}
+	return ""
+}
+
+func (m *SendCommandResponse) GetErrorJson() string {
+	if m != nil {
+		return m.ErrorJson
+	}
+	return ""
+}
+
+func (m *SendCommandResponse) GetCacheSeconds() int64 {
+	if m != nil {
+		return m.CacheSeconds
+	}
+	return 0
+}
+
+type AnnounceStartupRequest struct {
+	Version  string `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	ServerId int32  `protobuf:"varint,2,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+}
+
+func (m *AnnounceStartupRequest) Reset()         { *m = AnnounceStartupRequest{} }
+func (m *AnnounceStartupRequest) String() string { return proto.CompactTextString(m) }
+func (*AnnounceStartupRequest) ProtoMessage()    {}
+
+func (m *AnnounceStartupRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *AnnounceStartupRequest) GetServerId() int32 {
+	if m != nil {
+		return m.ServerId
+	}
+	return 0
+}
+
+type AnnounceStartupResponse struct{}
+
+func (m *AnnounceStartupResponse) Reset()         { *m = AnnounceStartupResponse{} }
+func (m *AnnounceStartupResponse) String() string { return proto.CompactTextString(m) }
+func (*AnnounceStartupResponse) ProtoMessage()    {}
+
+type AddTopicRequest struct {
+	Channels []string `protobuf:"bytes,1,rep,name=channels,proto3" json:"channels,omitempty"`
+}
+
+func (m *AddTopicRequest) Reset()         { *m = AddTopicRequest{} }
+func (m *AddTopicRequest) String() string { return proto.CompactTextString(m) }
+func (*AddTopicRequest) ProtoMessage()    {}
+
+func (m *AddTopicRequest) GetChannels() []string {
+	if m != nil {
+		return m.Channels
+	}
+	return nil
+}
+
+type RemoveTopicsRequest struct {
+	Channels []string `protobuf:"bytes,1,rep,name=channels,proto3" json:"channels,omitempty"`
+}
+
+func (m *RemoveTopicsRequest) Reset()         { *m = RemoveTopicsRequest{} }
+func (m *RemoveTopicsRequest) String() string { return proto.CompactTextString(m) }
+func (*RemoveTopicsRequest) ProtoMessage()    {}
+
+func (m *RemoveTopicsRequest) GetChannels() []string {
+	if m != nil {
+		return m.Channels
+	}
+	return nil
+}
+
+type TopicNoticeResponse struct{}
+
+func (m *TopicNoticeResponse) Reset()         { *m = TopicNoticeResponse{} }
+func (m *TopicNoticeResponse) String() string { return proto.CompactTextString(m) }
+func (*TopicNoticeResponse) ProtoMessage()    {}
+
+type PostStatisticsRequest struct {
+	Fields map[string]string `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *PostStatisticsRequest) Reset()         { *m = PostStatisticsRequest{} }
+func (m *PostStatisticsRequest) String() string { return proto.CompactTextString(m) }
+func (*PostStatisticsRequest) ProtoMessage()    {}
+
+func (m *PostStatisticsRequest) GetFields() map[string]string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type PostStatisticsResponse struct{}
+
+func (m *PostStatisticsResponse) Reset()         { *m = PostStatisticsResponse{} }
+func (m *PostStatisticsResponse) String() string { return proto.CompactTextString(m) }
+func (*PostStatisticsResponse) ProtoMessage()    {}
@@ -0,0 +1,77 @@
+// This file is hand-maintained, matching the shape protoc-gen-go-grpc would
+// generate for the BackendService defined in backend.proto — see the
+// package comment in backend.pb.go for why there is no protoc/buf step.
+package backendpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// BackendServiceClient is the client API for BackendService.
+type BackendServiceClient interface {
+	SendCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*SendCommandResponse, error)
+	AnnounceStartup(ctx context.Context, in *AnnounceStartupRequest, opts ...grpc.CallOption) (*AnnounceStartupResponse, error)
+	AddTopic(ctx context.Context, in *AddTopicRequest, opts ...grpc.CallOption) (*TopicNoticeResponse, error)
+	RemoveTopics(ctx context.Context, in *RemoveTopicsRequest, opts ...grpc.CallOption) (*TopicNoticeResponse, error)
+	PostStatistics(ctx context.Context, in *PostStatisticsRequest, opts ...grpc.CallOption) (*PostStatisticsResponse, error)
+}
+
+type backendServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewBackendServiceClient wraps an established gRPC connection as a BackendServiceClient.
+func NewBackendServiceClient(cc grpc.ClientConnInterface) BackendServiceClient {
+	return &backendServiceClient{cc}
+}
+
+func (c *backendServiceClient) SendCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*SendCommandResponse, error) {
+	out := new(SendCommandResponse)
+	if err := c.cc.Invoke(ctx, "/backendpb.BackendService/SendCommand", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) AnnounceStartup(ctx context.Context, in *AnnounceStartupRequest, opts ...grpc.CallOption) (*AnnounceStartupResponse, error) {
+	out := new(AnnounceStartupResponse)
+	if err := c.cc.Invoke(ctx, "/backendpb.BackendService/AnnounceStartup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) AddTopic(ctx context.Context, in *AddTopicRequest, opts ...grpc.CallOption) (*TopicNoticeResponse, error) {
+	out := new(TopicNoticeResponse)
+	if err := c.cc.Invoke(ctx, "/backendpb.BackendService/AddTopic", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) RemoveTopics(ctx context.Context, in *RemoveTopicsRequest, opts ...grpc.CallOption) (*TopicNoticeResponse, error) {
+	out := new(TopicNoticeResponse)
+	if err := c.cc.Invoke(ctx, "/backendpb.BackendService/RemoveTopics", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backendServiceClient) PostStatistics(ctx context.Context, in *PostStatisticsRequest, opts ...grpc.CallOption) (*PostStatisticsResponse, error) {
+	out := new(PostStatisticsResponse)
+	if err := c.cc.Invoke(ctx, "/backendpb.BackendService/PostStatistics", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BackendServiceServer is the server API for BackendService.
+type BackendServiceServer interface {
+	SendCommand(context.Context, *SendCommandRequest) (*SendCommandResponse, error)
+	AnnounceStartup(context.Context, *AnnounceStartupRequest) (*AnnounceStartupResponse, error)
+	AddTopic(context.Context, *AddTopicRequest) (*TopicNoticeResponse, error)
+	RemoveTopics(context.Context, *RemoveTopicsRequest) (*TopicNoticeResponse, error)
+	PostStatistics(context.Context, *PostStatisticsRequest) (*PostStatisticsResponse, error)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"fmt"
+)
+
+// ClusterEvent is a cluster-wide notification that a topic gained its first
+// subscriber, or lost its last one, somewhere in the fleet.
+type ClusterEvent struct {
+	Topic string
+	Added bool
+}
+
+// ClusterBus lets every socketserver node agree on cluster-wide reference
+// counts for pub/sub topics, so the backend is told "added=t" only the
+// first time any node subscribes to a topic and "added=f" only when no node
+// has subscribers left — instead of every node reporting its own local
+// subscription count independently.
+//
+// There is deliberately no leader election: PublishSubscribe and
+// PublishUnsubscribe are atomic increment/decrement operations against a
+// shared counter, and whichever node's call happens to cross the 0/1
+// boundary is the one responsible for notifying the backend.
+type ClusterBus interface {
+	// PublishSubscribe increments the cluster-wide subscriber count for topic.
+	// firstSubscriber is true if this call took the count from 0 to 1, meaning
+	// this node is the one that should tell the backend "added=t".
+	PublishSubscribe(ctx context.Context, topic string) (firstSubscriber bool, err error)
+
+	// PublishUnsubscribe decrements the cluster-wide subscriber count for topic.
+	// lastSubscriber is true if this call took the count down to 0, meaning
+	// this node is the one that should tell the backend "added=f".
+	PublishUnsubscribe(ctx context.Context, topic string) (lastSubscriber bool, err error)
+
+	// Events streams every PublishSubscribe/PublishUnsubscribe that crossed the
+	// 0/1 boundary, fleet-wide, for consumers that just want to observe
+	// cluster-wide topic activity (e.g. logging/metrics) without talking to
+	// Redis or NATS directly.
+	Events() <-chan ClusterEvent
+
+	Close() error
+}
+
+// newClusterBus builds the ClusterBus selected by config.ClusterBus
+// ("none", the default single-node behavior, "redis", or "nats").
+func newClusterBus(config *ConfigFile) (ClusterBus, error) {
+	switch config.ClusterBus {
+	case "", "none":
+		return newLocalClusterBus(), nil
+	case "redis":
+		return newRedisClusterBus(config.ClusterBusRedisURL, config.ClusterBusKeyPrefix)
+	case "nats":
+		return newNATSClusterBus(config.ClusterBusNATSURL, config.ClusterBusKeyPrefix)
+	default:
+		return nil, fmt.Errorf("unknown ClusterBus %q", config.ClusterBus)
+	}
+}
+
+// localClusterBus is the single-node ClusterBus: every node is the only
+// node, so every subscribe is a first subscriber and every unsubscribe is
+// the last. This keeps a single socketserver's behavior identical to
+// before ClusterBus existed, with no Redis or NATS dependency required.
+type localClusterBus struct {
+	events chan ClusterEvent
+}
+
+func newLocalClusterBus() *localClusterBus {
+	return &localClusterBus{events: make(chan ClusterEvent, 64)}
+}
+
+func (l *localClusterBus) PublishSubscribe(ctx context.Context, topic string) (bool, error) {
+	l.emit(ClusterEvent{Topic: topic, Added: true})
+	return true, nil
+}
+
+func (l *localClusterBus) PublishUnsubscribe(ctx context.Context, topic string) (bool, error) {
+	l.emit(ClusterEvent{Topic: topic, Added: false})
+	return true, nil
+}
+
+func (l *localClusterBus) emit(evt ClusterEvent) {
+	select {
+	case l.events <- evt:
+	default:
+		// A full events buffer means nobody's listening; never block the
+		// subscribe/unsubscribe path on it.
+	}
+}
+
+func (l *localClusterBus) Events() <-chan ClusterEvent {
+	return l.events
+}
+
+func (l *localClusterBus) Close() error {
+	close(l.events)
+	return nil
+}
+
+// crossedToFirstSubscriber reports whether incrementing a cluster-wide
+// subscriber counter to newCount means this increment was the one that took
+// it from 0 to 1 — i.e. this node should tell the backend "added=t".
+func crossedToFirstSubscriber(newCount int64) bool {
+	return newCount == 1
+}
+
+// crossedToLastSubscriber reports whether decrementing a cluster-wide
+// subscriber counter to newCount means this decrement was the one that took
+// it down to (or below) 0 — i.e. this node should tell the backend
+// "added=f". "Or below" absorbs a counter that was never incremented on
+// this node's view of the world (e.g. after a restart), instead of drifting
+// further negative forever.
+func crossedToLastSubscriber(newCount int64) bool {
+	return newCount <= 0
+}
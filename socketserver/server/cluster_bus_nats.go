@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsClusterBus is a ClusterBus backed by a NATS JetStream key-value bucket
+// for the per-topic counter (updated via a compare-and-swap retry loop,
+// since core NATS has no atomic INCR) and a plain NATS subject for
+// broadcasting cluster-wide events.
+type natsClusterBus struct {
+	conn          *nats.Conn
+	js            nats.JetStreamContext
+	kv            nats.KeyValue
+	eventsSubject string
+	sub           *nats.Subscription
+	events        chan ClusterEvent
+}
+
+const natsClusterBusBucket = "ffz-cluster-topics"
+
+// newNATSClusterBus connects to the NATS server at natsURL and namespaces
+// the events subject under keyPrefix. The per-topic counters in
+// natsClusterBusBucket are keyed by the bare topic name and are not
+// prefixed, so every socketserver on the NATS cluster shares them.
+func newNATSClusterBus(natsURL, keyPrefix string) (*natsClusterBus, error) {
+	conn, err := nats.Connect(natsURL)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to nats cluster bus: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("opening nats jetstream context: %w", err)
+	}
+
+	kv, err := js.KeyValue(natsClusterBusBucket)
+	if err != nil {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: natsClusterBusBucket})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("creating nats cluster topic bucket: %w", err)
+		}
+	}
+
+	n := &natsClusterBus{
+		conn:          conn,
+		js:            js,
+		kv:            kv,
+		eventsSubject: keyPrefix + "topic-events",
+		events:        make(chan ClusterEvent, 64),
+	}
+
+	sub, err := conn.Subscribe(n.eventsSubject, n.handleEvent)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to nats cluster topic events: %w", err)
+	}
+	n.sub = sub
+
+	return n, nil
+}
+
+// casIncrement applies delta to the counter stored for topic under a
+// compare-and-swap retry loop, since JetStream's KV store has no native
+// atomic INCR/DECR. It returns the counter's value after the update.
+func (n *natsClusterBus) casIncrement(topic string, delta int64) (int64, error) {
+	for {
+		entry, err := n.kv.Get(topic)
+		if err != nil && err != nats.ErrKeyNotFound {
+			return 0, err
+		}
+
+		var current int64
+		var revision uint64
+		if err == nil {
+			current = int64(binary.BigEndian.Uint64(entry.Value()))
+			revision = entry.Revision()
+		}
+
+		next := current + delta
+		nextBytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(nextBytes, uint64(next))
+
+		if err == nats.ErrKeyNotFound {
+			_, err = n.kv.Create(topic, nextBytes)
+		} else {
+			_, err = n.kv.Update(topic, nextBytes, revision)
+		}
+		if err == nil {
+			return next, nil
+		}
+		// Another node updated the counter between our Get and our
+		// Create/Update; retry against the new revision.
+	}
+}
+
+func (n *natsClusterBus) PublishSubscribe(ctx context.Context, topic string) (bool, error) {
+	count, err := n.casIncrement(topic, 1)
+	if err != nil {
+		return false, fmt.Errorf("incrementing cluster subscriber count for %q: %w", topic, err)
+	}
+
+	first := crossedToFirstSubscriber(count)
+	if first {
+		n.publishEvent(ClusterEvent{Topic: topic, Added: true})
+	}
+	return first, nil
+}
+
+func (n *natsClusterBus) PublishUnsubscribe(ctx context.Context, topic string) (bool, error) {
+	count, err := n.casIncrement(topic, -1)
+	if err != nil {
+		return false, fmt.Errorf("decrementing cluster subscriber count for %q: %w", topic, err)
+	}
+
+	if crossedToLastSubscriber(count) {
+		if err := n.kv.Delete(topic); err != nil && err != nats.ErrKeyNotFound {
+			return false, fmt.Errorf("clearing cluster subscriber count for %q: %w", topic, err)
+		}
+		n.publishEvent(ClusterEvent{Topic: topic, Added: false})
+		return true, nil
+	}
+	return false, nil
+}
+
+func (n *natsClusterBus) publishEvent(evt ClusterEvent) {
+	payload := []byte(evt.Topic)
+	if evt.Added {
+		payload = append(payload, byte(1))
+	} else {
+		payload = append(payload, byte(0))
+	}
+	_ = n.conn.Publish(n.eventsSubject, payload)
+}
+
+func (n *natsClusterBus) handleEvent(msg *nats.Msg) {
+	if len(msg.Data) == 0 {
+		return
+	}
+	evt := ClusterEvent{
+		Topic: string(msg.Data[:len(msg.Data)-1]),
+		Added: msg.Data[len(msg.Data)-1] == 1,
+	}
+	select {
+	case n.events <- evt:
+	default:
+	}
+}
+
+func (n *natsClusterBus) Events() <-chan ClusterEvent {
+	return n.events
+}
+
+func (n *natsClusterBus) Close() error {
+	if n.sub != nil {
+		_ = n.sub.Unsubscribe()
+	}
+	n.conn.Close()
+	return nil
+}
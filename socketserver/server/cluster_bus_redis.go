@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisClusterBus is a ClusterBus backed by a Redis INCR/DECR counter per
+// topic, namespaced under keyPrefix so it can share a Redis instance with
+// redisResponseCache without colliding. Cluster-wide events (a topic
+// crossing the 0/1 subscriber boundary) are broadcast over a Pub/Sub
+// channel so every node's Events() sees them, not just the node that
+// happened to cross the boundary.
+type redisClusterBus struct {
+	client        *redis.Client
+	keyPrefix     string
+	eventsChannel string
+	events        chan ClusterEvent
+}
+
+// newRedisClusterBus connects to Redis at redisURL and namespaces every
+// counter key (and the events Pub/Sub channel) under keyPrefix.
+func newRedisClusterBus(redisURL, keyPrefix string) (*redisClusterBus, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing redis cluster bus URL: %w", err)
+	}
+
+	r := &redisClusterBus{
+		client:        redis.NewClient(opts),
+		keyPrefix:     keyPrefix,
+		eventsChannel: keyPrefix + "topic-events",
+		events:        make(chan ClusterEvent, 64),
+	}
+
+	if err := r.client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("connecting to redis cluster bus: %w", err)
+	}
+
+	go r.subscribeEvents()
+
+	return r, nil
+}
+
+func (r *redisClusterBus) counterKey(topic string) string {
+	return r.keyPrefix + "topic-count/" + topic
+}
+
+func (r *redisClusterBus) PublishSubscribe(ctx context.Context, topic string) (bool, error) {
+	count, err := r.client.Incr(ctx, r.counterKey(topic)).Result()
+	if err != nil {
+		return false, fmt.Errorf("incrementing cluster subscriber count for %q: %w", topic, err)
+	}
+
+	first := crossedToFirstSubscriber(count)
+	if first {
+		r.publishEvent(ctx, ClusterEvent{Topic: topic, Added: true})
+	}
+	return first, nil
+}
+
+func (r *redisClusterBus) PublishUnsubscribe(ctx context.Context, topic string) (bool, error) {
+	count, err := r.client.Decr(ctx, r.counterKey(topic)).Result()
+	if err != nil {
+		return false, fmt.Errorf("decrementing cluster subscriber count for %q: %w", topic, err)
+	}
+
+	if crossedToLastSubscriber(count) {
+		// A count below zero means this key had never been incremented on this
+		// node (e.g. after a restart); treat it the same as hitting exactly
+		// zero and reset it so it doesn't drift further negative.
+		r.client.Del(ctx, r.counterKey(topic))
+		r.publishEvent(ctx, ClusterEvent{Topic: topic, Added: false})
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *redisClusterBus) publishEvent(ctx context.Context, evt ClusterEvent) {
+	raw, err := json.Marshal(evt)
+	if err != nil {
+		return
+	}
+	r.client.Publish(ctx, r.eventsChannel, raw)
+}
+
+// subscribeEvents forwards every cluster-wide topic event published by any
+// node (including this one) onto the local Events() channel.
+func (r *redisClusterBus) subscribeEvents() {
+	sub := r.client.Subscribe(context.Background(), r.eventsChannel)
+	defer sub.Close()
+
+	for msg := range sub.Channel() {
+		var evt ClusterEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &evt); err != nil {
+			continue
+		}
+		select {
+		case r.events <- evt:
+		default:
+		}
+	}
+}
+
+func (r *redisClusterBus) Events() <-chan ClusterEvent {
+	return r.events
+}
+
+func (r *redisClusterBus) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCrossedToFirstSubscriber(t *testing.T) {
+	cases := []struct {
+		count int64
+		want  bool
+	}{
+		{count: 0, want: false},
+		{count: 1, want: true},
+		{count: 2, want: false},
+		{count: 5, want: false},
+	}
+	for _, c := range cases {
+		if got := crossedToFirstSubscriber(c.count); got != c.want {
+			t.Errorf("crossedToFirstSubscriber(%d) = %v, want %v", c.count, got, c.want)
+		}
+	}
+}
+
+func TestCrossedToLastSubscriber(t *testing.T) {
+	cases := []struct {
+		count int64
+		want  bool
+	}{
+		{count: 2, want: false},
+		{count: 1, want: false},
+		{count: 0, want: true},
+		{count: -1, want: true}, // a counter that drifted negative is still treated as drained
+	}
+	for _, c := range cases {
+		if got := crossedToLastSubscriber(c.count); got != c.want {
+			t.Errorf("crossedToLastSubscriber(%d) = %v, want %v", c.count, got, c.want)
+		}
+	}
+}
+
+func TestLocalClusterBusEverySubscribeIsFirstAndLast(t *testing.T) {
+	bus := newLocalClusterBus()
+	defer bus.Close()
+
+	ctx := context.Background()
+
+	first, err := bus.PublishSubscribe(ctx, "room.trihex")
+	if err != nil {
+		t.Fatalf("PublishSubscribe: %v", err)
+	}
+	if !first {
+		t.Fatal("a single-node bus's only subscriber should always be the first")
+	}
+
+	last, err := bus.PublishUnsubscribe(ctx, "room.trihex")
+	if err != nil {
+		t.Fatalf("PublishUnsubscribe: %v", err)
+	}
+	if !last {
+		t.Fatal("a single-node bus's only unsubscriber should always be the last")
+	}
+
+	select {
+	case evt := <-bus.Events():
+		if evt.Topic != "room.trihex" || !evt.Added {
+			t.Fatalf("unexpected first event: %+v", evt)
+		}
+	default:
+		t.Fatal("expected a subscribe event on the Events() channel")
+	}
+
+	select {
+	case evt := <-bus.Events():
+		if evt.Topic != "room.trihex" || evt.Added {
+			t.Fatalf("unexpected second event: %+v", evt)
+		}
+	default:
+		t.Fatal("expected an unsubscribe event on the Events() channel")
+	}
+}
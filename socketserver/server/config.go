@@ -0,0 +1,72 @@
+package server
+
+import "time"
+
+// ConfigFile is the on-disk JSON configuration for a socketserver node. It is
+// loaded at startup and passed to setupBackend, newResponseCache, and every
+// other constructor that needs operator-tunable settings.
+type ConfigFile struct {
+	ListenAddr      string
+	SSLListenAddr   string
+	MinMemoryKBytes int64
+
+	// ServerID and the NaCl keypair identify this node to the backend, and
+	// are generated by GenerateKeys.
+	ServerID         int
+	OurPublicKey     []byte
+	OurPrivateKey    []byte
+	BackendPublicKey []byte
+
+	// BackendURL is the default (and, absent a backend pool, only) backend
+	// endpoint's base URL, used for the "default" backendEndpoint.
+	BackendURL string
+
+	// CacheBackend selects the ResponseCache implementation: "" or "memory"
+	// (the default, in-process) or "redis" (shared across nodes).
+	CacheBackend   string
+	CacheRedisURL  string
+	CacheKeyPrefix string
+
+	// BackendAdminSecret guards the operator-facing admin HTTP handlers
+	// (AdminBackendsHandler, AdminCacheHandler); requests must send it back
+	// as the X-FFZ-Admin-Secret header. Leaving it empty disables them
+	// entirely.
+	BackendAdminSecret string
+
+	// BackendTransport selects the BackendTransport implementation for every
+	// endpoint in the pool: "" or "http" (the default, NaCl-sealed form
+	// POSTs) or "grpc" (mTLS + protobuf). BackendGRPCAddr is the "default"
+	// endpoint's gRPC address, paired with BackendURL for the HTTP address;
+	// the mTLS material is shared by every endpoint regardless of address.
+	BackendTransport      string
+	BackendGRPCAddr       string
+	BackendGRPCClientCert string
+	BackendGRPCClientKey  string
+	BackendGRPCCACert     string
+
+	// BackendMaxRetries and BackendRetryBaseDelay govern withEndpoint's
+	// retry loop; BackendBreakerThreshold and BackendBreakerCooldown govern
+	// each backendEndpoint's circuit breaker. Zero/negative values fall
+	// back to the defaultMaxRetries/defaultRetryBaseDelay/
+	// defaultBreakerThreshold/defaultBreakerCooldown constants.
+	BackendMaxRetries       int
+	BackendRetryBaseDelay   time.Duration
+	BackendBreakerThreshold int
+	BackendBreakerCooldown  time.Duration
+
+	// ClusterBus selects the ClusterBus implementation: "" or "none" (the
+	// default, single-node behavior), "redis", or "nats". ClusterBusRedisURL
+	// and ClusterBusNATSURL are the respective server addresses;
+	// ClusterBusKeyPrefix namespaces the Redis counter keys and the events
+	// channel/subject the same way CacheKeyPrefix namespaces response cache
+	// keys. The NATS bus keeps its counters in the fixed natsClusterBusBucket
+	// under the bare topic name, without the prefix.
+	ClusterBus          string
+	ClusterBusRedisURL  string
+	ClusterBusNATSURL   string
+	ClusterBusKeyPrefix string
+}
+
+// defaultMinMemoryKB is the MinMemoryKBytes GenerateKeys writes out for a
+// freshly generated configuration file.
+const defaultMinMemoryKB = 128 * 1024
@@ -0,0 +1,13 @@
+package server
+
+import "net/http"
+
+// RegisterAdminRoutes wires backend's admin HTTP surface (backend pool
+// management, cache invalidation, and per-endpoint health) onto mux, for the
+// main server's http.ServeMux to mount alongside the client-facing routes.
+func (backend *backendInfo) RegisterAdminRoutes(mux *http.ServeMux) {
+	mux.Handle("/admin/backends", backend.AdminBackendsHandler())
+	mux.Handle("/admin/backends/", backend.AdminBackendsHandler())
+	mux.Handle("/admin/cache/", backend.AdminCacheHandler())
+	mux.Handle("/admin/stats", StatisticsHandler())
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	cache "github.com/patrickmn/go-cache"
+)
+
+// ResponseCache is the storage backend behind backendInfo.responseCache.
+//
+// It exists so a socketserver running behind a load balancer can share
+// cached backend RPC responses across every node instead of each node only
+// benefiting from its own `FFZ-Cache` hits. The in-memory implementation is
+// the default and matches the old hard-coded go-cache behavior; Redis is
+// available for multi-node deployments.
+type ResponseCache interface {
+	// Get returns the cached entry for key, if any.
+	Get(key string) (cacheEntry, bool)
+	// SetWithTTL stores entry under key, to be evicted automatically after ttl.
+	SetWithTTL(key string, entry cacheEntry, ttl time.Duration)
+	// Delete removes key from the cache, on every node sharing this cache.
+	Delete(key string)
+}
+
+// memoryResponseCache is a ResponseCache backed by an in-process go-cache.
+//
+// It is the original behavior of backendInfo.responseCache, and is the right
+// choice for a single-node deployment.
+type memoryResponseCache struct {
+	cache *cache.Cache
+}
+
+func newMemoryResponseCache() *memoryResponseCache {
+	return &memoryResponseCache{cache: cache.New(60*time.Second, 10*time.Minute)}
+}
+
+func (m *memoryResponseCache) Get(key string) (cacheEntry, bool) {
+	val, ok := m.cache.Get(key)
+	if !ok {
+		return cacheEntry{}, false
+	}
+	return val.(cacheEntry), true
+}
+
+func (m *memoryResponseCache) SetWithTTL(key string, entry cacheEntry, ttl time.Duration) {
+	m.cache.Set(key, entry, ttl)
+}
+
+func (m *memoryResponseCache) Delete(key string) {
+	m.cache.Delete(key)
+}
+
+// redisResponseCache is a ResponseCache backed by Redis, shared by every
+// socketserver node that is configured with the same RedisURL and KeyPrefix.
+//
+// A Delete call on one node removes the shared key, so the entry is gone for
+// every node at once; the delete is also published on a per-namespace
+// invalidation channel (see subscribeInvalidations).
+type redisResponseCache struct {
+	client            *redis.Client
+	keyPrefix         string
+	invalidateChannel string
+}
+
+// newRedisResponseCache connects to Redis at redisURL and namespaces every
+// key (and the invalidation pub/sub channel) under keyPrefix, so multiple
+// socketservers can share one Redis instance without colliding.
+func newRedisResponseCache(redisURL, keyPrefix string) (*redisResponseCache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing redis cache URL: %w", err)
+	}
+
+	r := &redisResponseCache{
+		client:            redis.NewClient(opts),
+		keyPrefix:         keyPrefix,
+		invalidateChannel: keyPrefix + "invalidate",
+	}
+
+	if err := r.client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("connecting to redis cache: %w", err)
+	}
+
+	go r.subscribeInvalidations()
+
+	return r, nil
+}
+
+func (r *redisResponseCache) namespacedKey(key string) string {
+	return r.keyPrefix + key
+}
+
+func (r *redisResponseCache) Get(key string) (cacheEntry, bool) {
+	raw, err := r.client.Get(context.Background(), r.namespacedKey(key)).Bytes()
+	if err != nil {
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (r *redisResponseCache) SetWithTTL(key string, entry cacheEntry, ttl time.Duration) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	r.client.Set(context.Background(), r.namespacedKey(key), raw, ttl)
+}
+
+func (r *redisResponseCache) Delete(key string) {
+	ctx := context.Background()
+	r.client.Del(ctx, r.namespacedKey(key))
+	r.client.Publish(ctx, r.invalidateChannel, key)
+}
+
+// subscribeInvalidations drains the invalidation channel so that Redis's
+// pubsub buffer for it never backs up. Redis is itself the shared store, so
+// a Delete on one node is already visible to every other node as soon as the
+// key is gone; this loop exists as a hook point for cache-level logging or
+// metrics on cross-node invalidation.
+func (r *redisResponseCache) subscribeInvalidations() {
+	sub := r.client.Subscribe(context.Background(), r.invalidateChannel)
+	defer sub.Close()
+
+	for range sub.Channel() {
+	}
+}
+
+// newResponseCache builds the ResponseCache selected by config.CacheBackend
+// ("memory", the default, or "redis").
+func newResponseCache(config *ConfigFile) (ResponseCache, error) {
+	switch config.CacheBackend {
+	case "", "memory":
+		return newMemoryResponseCache(), nil
+	case "redis":
+		return newRedisResponseCache(config.CacheRedisURL, config.CacheKeyPrefix)
+	default:
+		return nil, fmt.Errorf("unknown CacheBackend %q", config.CacheBackend)
+	}
+}
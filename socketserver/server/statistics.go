@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Statistics is the JSON body served by the stats/health endpoint,
+// describing this node's current state.
+type Statistics struct {
+	Health struct {
+		// Backend is the last successful call time for every backend pool
+		// endpoint, keyed by endpoint ID. See backendInfo.BackendHealth.
+		Backend map[string]time.Time `json:"backend"`
+	} `json:"health"`
+}
+
+// CollectStatistics assembles a Statistics snapshot for this node.
+func CollectStatistics() Statistics {
+	var stats Statistics
+	stats.Health.Backend = Backend.BackendHealth()
+	return stats
+}
+
+// StatisticsHandler serves GET requests with a JSON-encoded Statistics
+// snapshot; RegisterAdminRoutes mounts it at /admin/stats.
+func StatisticsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(CollectStatistics())
+	})
+}